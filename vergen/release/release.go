@@ -0,0 +1,203 @@
+// Package release provides a release-preparation helper built on top of
+// vergen: it bumps a VERSION file, validates a CHANGELOG entry, commits
+// and tags the release, and regenerates version.go.
+package release
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Akagi201/utils-go/vergen"
+)
+
+var semverRe = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// Errors returned by PrepareRelease so callers can distinguish why it
+// refused to run.
+var (
+	ErrDirty            = errors.New("release: working tree has uncommitted changes")
+	ErrWrongBranch      = errors.New("release: not on the master/main branch")
+	ErrNoChangelogEntry = errors.New("release: no changelog entry found for this version")
+)
+
+// ReleaseOptions configures PrepareRelease.
+type ReleaseOptions struct {
+	// Version is the version being released, e.g. "1.2.3" (no leading "v").
+	Version string
+
+	// VersionFile is the VERSION file PrepareRelease rewrites. Defaults
+	// to "VERSION".
+	VersionFile string
+
+	// GoVersionFile, if set, is a Go source file containing a line that
+	// GoVersionRegexp matches, which PrepareRelease rewrites to the new
+	// version.
+	GoVersionFile string
+
+	// GoVersionRegexp matches the version literal to rewrite in
+	// GoVersionFile. It must contain a capture group, and its first
+	// capture group must wrap the literal itself (e.g.
+	// `const AppVersion = "(.*)"`); only that group is replaced, so the rest of the matched statement is left
+	// untouched. Defaults to `var version = "(.*)"`.
+	GoVersionRegexp string
+
+	// ChangelogFile is checked for an entry mentioning Version. Defaults
+	// to "CHANGELOG.md".
+	ChangelogFile string
+
+	// IgnoreBranchName skips the master/main branch check.
+	IgnoreBranchName bool
+
+	// IgnoreUncommittedChanges skips the dirty working tree check.
+	IgnoreUncommittedChanges bool
+}
+
+// PrepareRelease validates and prepares a release: it checks the version
+// string, the working tree and branch, rewrites VERSION (and optionally a
+// Go source file), verifies a CHANGELOG.md entry exists, commits the
+// changes with a canonical message, creates an annotated git tag, and
+// finally regenerates version.go via vergen.Create().
+func PrepareRelease(opts ReleaseOptions) error {
+	if !semverRe.MatchString(opts.Version) {
+		return fmt.Errorf("release: invalid version %q, want X.Y.Z", opts.Version)
+	}
+	if opts.VersionFile == "" {
+		opts.VersionFile = "VERSION"
+	}
+	if opts.ChangelogFile == "" {
+		opts.ChangelogFile = "CHANGELOG.md"
+	}
+	if opts.GoVersionRegexp == "" {
+		opts.GoVersionRegexp = `var version = "(.*)"`
+	}
+
+	if !opts.IgnoreUncommittedChanges {
+		dirty, err := workingTreeDirty()
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return ErrDirty
+		}
+	}
+
+	if !opts.IgnoreBranchName {
+		branch, err := vergen.CurrentVCS().Branch(context.Background())
+		if err != nil {
+			return err
+		}
+		if branch != "master" && branch != "main" {
+			return ErrWrongBranch
+		}
+	}
+
+	if err := verifyChangelogEntry(opts.ChangelogFile, opts.Version); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(opts.VersionFile, []byte(opts.Version+"\n"), 0644); err != nil {
+		return err
+	}
+
+	if opts.GoVersionFile != "" {
+		if err := rewriteGoVersionFile(opts.GoVersionFile, opts.GoVersionRegexp, opts.Version); err != nil {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("Release v%s", opts.Version)
+	if err := gitCommit(message, opts.VersionFile, opts.GoVersionFile); err != nil {
+		return err
+	}
+	if err := gitTag("v"+opts.Version, message); err != nil {
+		return err
+	}
+
+	return vergen.Create()
+}
+
+// PrepareDev bumps VERSION to "<nextVersion>-dev" after a release, so the
+// working tree doesn't keep looking like it's on the version just tagged.
+func PrepareDev(nextVersion string) error {
+	return os.WriteFile("VERSION", []byte(nextVersion+"-dev\n"), 0644)
+}
+
+func workingTreeDirty() (bool, error) {
+	changed, err := vergen.CurrentVCS().Uncommitted(context.Background())
+	if err != nil {
+		return false, err
+	}
+	return len(changed) > 0, nil
+}
+
+func verifyChangelogEntry(filename, version string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("release: could not read %s: %w", filename, err)
+	}
+	if !strings.Contains(string(b), version) {
+		return ErrNoChangelogEntry
+	}
+	return nil
+}
+
+// rewriteGoVersionFile replaces the version literal captured by pattern's
+// first capture group with version, leaving the rest of the matched
+// statement (keyword, identifier, quoting, ...) untouched.
+func rewriteGoVersionFile(filename, pattern, version string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("release: invalid GoVersionRegexp: %w", err)
+	}
+	if re.NumSubexp() < 1 {
+		return fmt.Errorf("release: GoVersionRegexp %q has no capture group around the version literal", pattern)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	loc := re.FindSubmatchIndex(b)
+	if loc == nil {
+		return fmt.Errorf("release: no line in %s matched %q", filename, pattern)
+	}
+	start, end := loc[2], loc[3]
+	if start < 0 || end < 0 {
+		return fmt.Errorf("release: capture group in %q did not participate in the match in %s", pattern, filename)
+	}
+
+	var out bytes.Buffer
+	out.Write(b[:start])
+	out.WriteString(version)
+	out.Write(b[end:])
+	return os.WriteFile(filename, out.Bytes(), 0644)
+}
+
+func gitCommit(message string, files ...string) error {
+	var toAdd []string
+	for _, f := range files {
+		if f != "" {
+			toAdd = append(toAdd, f)
+		}
+	}
+	if err := runGit(append([]string{"add"}, toAdd...)...); err != nil {
+		return err
+	}
+	return runGit("commit", "-m", message)
+}
+
+func gitTag(tag, message string) error {
+	return runGit("tag", "-a", tag, "-m", message)
+}
+
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
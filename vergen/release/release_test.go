@@ -0,0 +1,75 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteGoVersionFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "version.go")
+
+	original := "package app\n\nconst AppVersion = \"0.9.0\"\n"
+	if err := os.WriteFile(filename, []byte(original), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := rewriteGoVersionFile(filename, `const AppVersion = "(.*)"`, "1.0.0"); err != nil {
+		t.Fatalf("rewriteGoVersionFile: %v", err)
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	want := "package app\n\nconst AppVersion = \"1.0.0\"\n"
+	if string(got) != want {
+		t.Errorf("rewriteGoVersionFile result = %q, want %q", got, want)
+	}
+}
+
+func TestRewriteGoVersionFileRequiresCaptureGroup(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "version.go")
+	if err := os.WriteFile(filename, []byte(`const AppVersion = "0.9.0"`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	err := rewriteGoVersionFile(filename, `const AppVersion = ".*"`, "1.0.0")
+	if err == nil {
+		t.Fatal("rewriteGoVersionFile with no capture group should fail, got nil error")
+	}
+}
+
+func TestVerifyChangelogEntry(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "CHANGELOG.md")
+	if err := os.WriteFile(filename, []byte("## 1.2.3\n\n- did a thing\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := verifyChangelogEntry(filename, "1.2.3"); err != nil {
+		t.Errorf("verifyChangelogEntry with matching entry: %v", err)
+	}
+	if err := verifyChangelogEntry(filename, "9.9.9"); err != ErrNoChangelogEntry {
+		t.Errorf("verifyChangelogEntry with missing entry = %v, want ErrNoChangelogEntry", err)
+	}
+}
+
+func TestSemverRe(t *testing.T) {
+	cases := []struct {
+		version string
+		valid   bool
+	}{
+		{"1.2.3", true},
+		{"v1.2.3", false},
+		{"1.2", false},
+		{"1.2.3-rc1", false},
+	}
+	for _, c := range cases {
+		if got := semverRe.MatchString(c.version); got != c.valid {
+			t.Errorf("semverRe.MatchString(%q) = %v, want %v", c.version, got, c.valid)
+		}
+	}
+}
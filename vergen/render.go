@@ -0,0 +1,199 @@
+package vergen
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PkgName is the package name vergen writes into Go-based renderers (and,
+// via Create(), the directory/file name version info is written to).
+// Defaults to DefaultPkgName.
+var PkgName = DefaultPkgName
+
+// ConstNames lets callers rename the constants/keys a Renderer emits,
+// instead of the fixed Vg*-prefixed names vergen has always used.
+type ConstNames struct {
+	Version     string
+	Semver      string
+	Hash        string
+	Clean       string
+	CommitTime  string
+	Branch      string
+	Tag         string
+	CommitCount string
+}
+
+// DefaultConstNames are the Vg*-prefixed names vergen has always used.
+var DefaultConstNames = ConstNames{
+	Version:     "VgVersion",
+	Semver:      "VgSemver",
+	Hash:        "VgHash",
+	Clean:       "VgClean",
+	CommitTime:  "VgCommitTime",
+	Branch:      "VgBranch",
+	Tag:         "VgTag",
+	CommitCount: "VgCommitCount",
+}
+
+// Names lets callers rename the constants CreateFile emits. Defaults to
+// DefaultConstNames.
+var Names = DefaultConstNames
+
+// Renderer turns gathered version data into the file contents CreateFile
+// writes out. pkgName and names come from the package-level PkgName and
+// Names variables at the time CreateFile is called.
+type Renderer func(pkgName string, names ConstNames, data versionData) (string, error)
+
+// Render is the Renderer CreateFile uses to produce its output. Defaults
+// to RenderGoConst, preserving vergen's original output format. Set it to
+// one of the other built-in Render* functions, or a custom Renderer, to
+// change formats.
+var Render Renderer = RenderGoConst
+
+// VersionInfo is the exported view of a CreateFile run's version data,
+// passed to the text/template executed by CreateFileWithTemplate and used
+// internally by the built-in renderers.
+type VersionInfo struct {
+	PkgName     string
+	Version     string
+	Semver      string
+	Hash        string
+	Clean       bool
+	CommitTime  string
+	Branch      string
+	Tag         string
+	CommitCount string
+}
+
+// toVersionInfo converts versionData into the exported VersionInfo shape.
+func (d versionData) toVersionInfo(pkgName string) VersionInfo {
+	return VersionInfo{
+		PkgName:     pkgName,
+		Version:     d.describeTags,
+		Semver:      d.semver,
+		Hash:        d.commit,
+		Clean:       !d.dirty,
+		CommitTime:  d.commitTime,
+		Branch:      d.branch,
+		Tag:         d.tag,
+		CommitCount: d.commitCount,
+	}
+}
+
+// RenderGoConst is vergen's original renderer: a Go source file declaring
+// the version info as untyped constants.
+func RenderGoConst(pkgName string, names ConstNames, data versionData) (string, error) {
+	v := data.toVersionInfo(pkgName)
+	return fmt.Sprintf(`package %s
+// auto generated by github.com/Akagi201/utils-go/vergen
+const (
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+	%s = %v
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+)
+`, v.PkgName,
+		names.Version, v.Version,
+		names.Semver, v.Semver,
+		names.Hash, v.Hash,
+		names.Clean, v.Clean,
+		names.CommitTime, v.CommitTime,
+		names.Branch, v.Branch,
+		names.Tag, v.Tag,
+		names.CommitCount, v.CommitCount,
+	), nil
+}
+
+// RenderGoVar renders the version info as a Go source file declaring var
+// (rather than const) symbols, so that they can be overridden at link
+// time with '-ldflags "-X pkg.VgVersion=..."', the way tools like s5cmd
+// and restic stamp their own version info. Every symbol is a string
+// except the Clean one, which is a bool; -X sets only string variables,
+// so that one cannot be overridden at link time.
+func RenderGoVar(pkgName string, names ConstNames, data versionData) (string, error) {
+	v := data.toVersionInfo(pkgName)
+	return fmt.Sprintf(`package %s
+// auto generated by github.com/Akagi201/utils-go/vergen
+// Override these at link time, e.g.:
+//   go build -ldflags "-X '%s.%s=...'"
+var (
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+	%s = %v
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+	%s = "%s"
+)
+`, v.PkgName, v.PkgName, names.Version,
+		names.Version, v.Version,
+		names.Semver, v.Semver,
+		names.Hash, v.Hash,
+		names.Clean, v.Clean,
+		names.CommitTime, v.CommitTime,
+		names.Branch, v.Branch,
+		names.Tag, v.Tag,
+		names.CommitCount, v.CommitCount,
+	), nil
+}
+
+// RenderJSON renders the version info as a JSON object, suitable for
+// writing to a file like version.json.
+func RenderJSON(pkgName string, names ConstNames, data versionData) (string, error) {
+	v := data.toVersionInfo(pkgName)
+	out := map[string]interface{}{
+		names.Version:     v.Version,
+		names.Semver:      v.Semver,
+		names.Hash:        v.Hash,
+		names.Clean:       v.Clean,
+		names.CommitTime:  v.CommitTime,
+		names.Branch:      v.Branch,
+		names.Tag:         v.Tag,
+		names.CommitCount: v.CommitCount,
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+// RenderEnv renders the version info as a '.env' file of KEY=value lines,
+// for consumption by shell scripts or tools that load environment files.
+func RenderEnv(pkgName string, names ConstNames, data versionData) (string, error) {
+	v := data.toVersionInfo(pkgName)
+	return fmt.Sprintf(
+		"%s=%s\n%s=%s\n%s=%s\n%s=%v\n%s=%s\n%s=%s\n%s=%s\n%s=%s\n",
+		names.Version, v.Version,
+		names.Semver, v.Semver,
+		names.Hash, v.Hash,
+		names.Clean, v.Clean,
+		names.CommitTime, v.CommitTime,
+		names.Branch, v.Branch,
+		names.Tag, v.Tag,
+		names.CommitCount, v.CommitCount,
+	), nil
+}
+
+// RenderMakefile renders the version info as a Makefile fragment of
+// simple variable assignments, for a top-level Makefile to 'include'.
+func RenderMakefile(pkgName string, names ConstNames, data versionData) (string, error) {
+	v := data.toVersionInfo(pkgName)
+	return fmt.Sprintf(
+		"# auto generated by github.com/Akagi201/utils-go/vergen\n"+
+			"%s := %s\n%s := %s\n%s := %s\n%s := %v\n%s := %s\n%s := %s\n%s := %s\n%s := %s\n",
+		names.Version, v.Version,
+		names.Semver, v.Semver,
+		names.Hash, v.Hash,
+		names.Clean, v.Clean,
+		names.CommitTime, v.CommitTime,
+		names.Branch, v.Branch,
+		names.Tag, v.Tag,
+		names.CommitCount, v.CommitCount,
+	), nil
+}
@@ -0,0 +1,85 @@
+package vergen
+
+import (
+	"strings"
+	"testing"
+)
+
+func testVersionData() versionData {
+	return versionData{
+		describeTags: "v1.0.0",
+		commit:       "0abcdef0123456789",
+		dirty:        false,
+		semver:       "v1.0.0",
+		commitTime:   "2024-01-02T03:04:05Z",
+		branch:       "main",
+		tag:          "v1.0.0",
+		commitCount:  "0",
+	}
+}
+
+func TestRenderGoConst(t *testing.T) {
+	out, err := RenderGoConst("version", DefaultConstNames, testVersionData())
+	if err != nil {
+		t.Fatalf("RenderGoConst: %v", err)
+	}
+	for _, want := range []string{
+		"package version", "const (",
+		`VgVersion = "v1.0.0"`, `VgHash = "0abcdef0123456789"`, "VgClean = true",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderGoConst output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderGoVar(t *testing.T) {
+	out, err := RenderGoVar("version", DefaultConstNames, testVersionData())
+	if err != nil {
+		t.Fatalf("RenderGoVar: %v", err)
+	}
+	if !strings.Contains(out, "var (") {
+		t.Errorf("RenderGoVar output should declare a var block, got:\n%s", out)
+	}
+	if !strings.Contains(out, `VgVersion = "v1.0.0"`) {
+		t.Errorf("RenderGoVar output missing VgVersion, got:\n%s", out)
+	}
+	if !strings.Contains(out, "VgClean = true") {
+		t.Errorf("RenderGoVar output should emit VgClean as an unquoted bool, got:\n%s", out)
+	}
+	if strings.Contains(out, `VgClean = "true"`) {
+		t.Errorf("RenderGoVar output must not quote VgClean, got:\n%s", out)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	out, err := RenderJSON("version", DefaultConstNames, testVersionData())
+	if err != nil {
+		t.Fatalf("RenderJSON: %v", err)
+	}
+	for _, want := range []string{`"VgVersion"`, `"v1.0.0"`, `"VgBranch"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("RenderJSON output missing %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderEnv(t *testing.T) {
+	out, err := RenderEnv("version", DefaultConstNames, testVersionData())
+	if err != nil {
+		t.Fatalf("RenderEnv: %v", err)
+	}
+	if !strings.Contains(out, "VgVersion=v1.0.0") {
+		t.Errorf("RenderEnv output missing VgVersion=v1.0.0, got:\n%s", out)
+	}
+}
+
+func TestRenderMakefile(t *testing.T) {
+	out, err := RenderMakefile("version", DefaultConstNames, testVersionData())
+	if err != nil {
+		t.Fatalf("RenderMakefile: %v", err)
+	}
+	if !strings.Contains(out, "VgVersion := v1.0.0") {
+		t.Errorf("RenderMakefile output missing VgVersion := v1.0.0, got:\n%s", out)
+	}
+}
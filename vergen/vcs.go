@@ -0,0 +1,339 @@
+package vergen
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VCS abstracts the version-control operations vergen needs in order to
+// generate version info. Implementing this interface lets vergen work
+// against backends other than Git (see GitVCS, HgVCS, FossilVCS) or
+// without any VCS at all (see NoVCS), e.g. in a tarball or CI environment.
+// Every method takes a context.Context so a caller-supplied timeout or
+// cancellation (see CreateWithContext) reaches the underlying command.
+type VCS interface {
+	// Describe returns a human readable description of the current
+	// revision, analogous to 'git describe --tags'.
+	Describe(ctx context.Context) (string, error)
+	// Revision returns the full identifier of the current revision.
+	Revision(ctx context.Context) (string, error)
+	// CommitTime returns the timestamp of the current revision.
+	CommitTime(ctx context.Context) (time.Time, error)
+	// Uncommitted returns the paths that have uncommitted changes.
+	Uncommitted(ctx context.Context) ([]string, error)
+	// Branch returns the name of the current branch.
+	Branch(ctx context.Context) (string, error)
+}
+
+// GitVCS is the VCS implementation backed by the git command line tool.
+// It is the VCS vergen has always used and remains the default whenever
+// a ".git" directory is found.
+type GitVCS struct{}
+
+// Describe implements VCS.
+func (GitVCS) Describe(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "git", "describe", "--tags")
+}
+
+// Revision implements VCS.
+func (GitVCS) Revision(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "git", "rev-parse", "HEAD")
+}
+
+// CommitTime implements VCS.
+func (GitVCS) CommitTime(ctx context.Context) (time.Time, error) {
+	s, err := runCommandSingleLineReturn(ctx, "git", "log", "-1", "--format=%cI")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Branch implements VCS.
+func (GitVCS) Branch(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// Uncommitted implements VCS.
+func (GitVCS) Uncommitted(ctx context.Context) ([]string, error) {
+	diffIndex, err := runCommandSingleLineReturn(ctx, "git", "diff-index", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return parseDiffIndexLines(diffIndex, -1), nil
+}
+
+// HgVCS is the VCS implementation backed by the Mercurial command line
+// tool ("hg"), for projects that use Hg instead of Git.
+type HgVCS struct{}
+
+// Describe implements VCS. The result has the same shape as 'git describe
+// --tags': the bare tag when on it, "<tag>-<count>-g<hash>" otherwise.
+func (HgVCS) Describe(ctx context.Context) (string, error) {
+	out, err := runCommandSingleLineReturn(ctx, "hg", "log", "-r", ".", "--template", "{latesttag}\n{latesttagdistance}\n{node|short}")
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(out, "\n")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("hg log: unexpected describe output %q", out)
+	}
+	if parts[1] == "0" {
+		return parts[0], nil
+	}
+	return parts[0] + "-" + parts[1] + "-g" + parts[2], nil
+}
+
+// Revision implements VCS.
+func (HgVCS) Revision(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "hg", "log", "-r", ".", "--template", "{node}")
+}
+
+// CommitTime implements VCS.
+func (HgVCS) CommitTime(ctx context.Context) (time.Time, error) {
+	s, err := runCommandSingleLineReturn(ctx, "hg", "log", "-r", ".", "--template", "{date|rfc3339date}")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Branch implements VCS.
+func (HgVCS) Branch(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "hg", "branch")
+}
+
+// Uncommitted implements VCS.
+func (HgVCS) Uncommitted(ctx context.Context) ([]string, error) {
+	status, err := runCommandSingleLineReturn(ctx, "hg", "status", "-mard")
+	if err != nil {
+		return nil, err
+	}
+	return parseDiffIndexLines(status, 1), nil
+}
+
+// FossilVCS is the VCS implementation backed by the Fossil command line
+// tool ("fossil"), for projects that use Fossil instead of Git or Hg.
+// Fossil has no direct equivalent of 'git describe'; see Describe.
+type FossilVCS struct{}
+
+// Describe implements VCS. Fossil has no "describe" command, so this
+// returns the most recent entry from 'fossil tag list', falling back to
+// the current checkout's revision if the repository has no tags.
+func (f FossilVCS) Describe(ctx context.Context) (string, error) {
+	tags, err := runCommandSingleLineReturn(ctx, "fossil", "tag", "list")
+	if err != nil {
+		return "", err
+	}
+	if tags == "" {
+		return f.Revision(ctx)
+	}
+	lines := strings.Split(tags, "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// Revision implements VCS.
+func (FossilVCS) Revision(ctx context.Context) (string, error) {
+	return fossilInfoField(ctx, "checkout")
+}
+
+// CommitTime implements VCS.
+func (FossilVCS) CommitTime(ctx context.Context) (time.Time, error) {
+	checkout, err := fossilInfoLine(ctx, "checkout")
+	if err != nil {
+		return time.Time{}, err
+	}
+	fields := strings.Fields(checkout)
+	if len(fields) < 3 {
+		return time.Time{}, fmt.Errorf("fossil info: unexpected checkout line %q", checkout)
+	}
+	return time.Parse("2006-01-02 15:04:05", fields[1]+" "+fields[2])
+}
+
+// Branch implements VCS.
+func (FossilVCS) Branch(ctx context.Context) (string, error) {
+	return runCommandSingleLineReturn(ctx, "fossil", "branch", "current")
+}
+
+// Uncommitted implements VCS.
+func (FossilVCS) Uncommitted(ctx context.Context) ([]string, error) {
+	changes, err := runCommandSingleLineReturn(ctx, "fossil", "changes")
+	if err != nil {
+		return nil, err
+	}
+	return parseDiffIndexLines(changes, 1), nil
+}
+
+// fossilInfoLine runs 'fossil info' and returns the raw value of field
+// (everything after its leading "field:"), e.g. field "checkout" returns
+// "<hash> 2023-01-02 15:04:05 UTC ...".
+func fossilInfoLine(ctx context.Context, field string) (string, error) {
+	info, err := runCommandSingleLineReturn(ctx, "fossil", "info")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) != field {
+			continue
+		}
+		return strings.TrimSpace(parts[1]), nil
+	}
+	return "", fmt.Errorf("fossil info: field %q not found", field)
+}
+
+// fossilInfoField returns just the first whitespace-separated token of a
+// 'fossil info' field, e.g. the hash out of the "checkout" field.
+func fossilInfoField(ctx context.Context, field string) (string, error) {
+	line, err := fossilInfoLine(ctx, field)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("fossil info: field %q is empty", field)
+	}
+	return fields[0], nil
+}
+
+// NoVCS is a VCS fallback for trees that aren't checked out from a
+// supported VCS at all, e.g. a release tarball or a CI job that only has
+// a shallow, history-less checkout. It reads its info from a plain
+// version file (by default one named "VERSION") instead of shelling out.
+type NoVCS struct {
+	// Filename is the file Describe() reads its version string from.
+	// Defaults to "VERSION" when empty.
+	Filename string
+}
+
+func (v NoVCS) filename() string {
+	if v.Filename == "" {
+		return "VERSION"
+	}
+	return v.Filename
+}
+
+// Describe implements VCS. It returns ErrNotARepo (wrapped with the
+// underlying os.ReadFile error) if Filename can't be read, so callers
+// don't mistake a missing/misnamed version file for a repo that
+// genuinely has no tags.
+func (v NoVCS) Describe(ctx context.Context) (string, error) {
+	b, err := os.ReadFile(v.filename())
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNotARepo, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Revision implements VCS.
+func (v NoVCS) Revision(ctx context.Context) (string, error) { return "", nil }
+
+// CommitTime implements VCS. There's no commit to read a time from, so
+// this is a stub gatherFromVCS never actually calls for NoVCS; it exists
+// only to satisfy the interface.
+func (v NoVCS) CommitTime(ctx context.Context) (time.Time, error) { return time.Time{}, nil }
+
+// Branch implements VCS. There's no branch to report, so this is a stub
+// gatherFromVCS never actually calls for NoVCS; it exists only to
+// satisfy the interface.
+func (v NoVCS) Branch(ctx context.Context) (string, error) { return "", nil }
+
+// Uncommitted implements VCS.
+func (v NoVCS) Uncommitted(ctx context.Context) ([]string, error) { return nil, nil }
+
+// currentVCS is the VCS backend CreateFile uses. It starts out unset so
+// that the first call auto-detects it; SetVCS overrides that.
+var currentVCS VCS
+
+// SetVCS overrides the automatically detected VCS backend, e.g. to inject
+// a custom implementation. Pass nil to restore auto-detection.
+func SetVCS(v VCS) {
+	currentVCS = v
+}
+
+// CurrentVCS returns the VCS backend CreateFile would use: whatever was
+// last passed to SetVCS, or the result of auto-detection otherwise. Other
+// packages that need to run their own VCS queries against the same
+// backend (e.g. vergen/release) should call this instead of assuming Git.
+func CurrentVCS() VCS {
+	return getVCS()
+}
+
+// getVCS returns the configured VCS backend, auto-detecting one by
+// walking upward from the current directory looking for a ".git", ".hg"
+// or Fossil checkout marker if none has been set yet. NoVCS is used as
+// the fallback when none is found.
+func getVCS() VCS {
+	if currentVCS == nil {
+		currentVCS = detectVCS()
+	}
+	return currentVCS
+}
+
+// detectVCS walks upward from the current directory looking for a ".git"
+// directory, a ".hg" directory, or a Fossil checkout marker file
+// (".fslckout" on Unix, "_FOSSIL_" on Windows), mirroring how the tools
+// themselves locate the repository root.
+func detectVCS() VCS {
+	dir, err := os.Getwd()
+	if err != nil {
+		return NoVCS{}
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return GitVCS{}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".hg")); err == nil {
+			return HgVCS{}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".fslckout")); err == nil {
+			return FossilVCS{}
+		}
+		if _, err := os.Stat(filepath.Join(dir, "_FOSSIL_")); err == nil {
+			return FossilVCS{}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return NoVCS{}
+}
+
+// isNotARepoMessage reports whether a command's stderr indicates that the
+// current directory isn't a repository of that VCS's kind.
+func isNotARepoMessage(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	return strings.Contains(lower, "not a git repository") ||
+		strings.Contains(lower, "no repository found") ||
+		strings.Contains(lower, "not within an open checkout")
+}
+
+// parseDiffIndexLines splits the line-oriented output of a status command
+// into a slice of affected file paths. field selects which whitespace-
+// separated field of each line holds the path (-1 means the last field).
+func parseDiffIndexLines(output string, field int) []string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil
+	}
+	lines := strings.Split(output, "\n")
+	files := make([]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		idx := field
+		if idx < 0 || idx >= len(fields) {
+			idx = len(fields) - 1
+		}
+		files = append(files, fields[idx])
+	}
+	return files
+}
@@ -0,0 +1,118 @@
+package vergen
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsNotARepoMessage(t *testing.T) {
+	cases := []struct {
+		name   string
+		stderr string
+		want   bool
+	}{
+		{"git", "fatal: not a git repository (or any of the parent directories): .git", true},
+		{"hg", "abort: no repository found in '/tmp' (.hg not found)!", true},
+		{"fossil", "fossil: not within an open checkout", true},
+		{"unrelated error", "fatal: ambiguous argument 'HEAD': unknown revision", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isNotARepoMessage(c.stderr); got != c.want {
+				t.Errorf("isNotARepoMessage(%q) = %v, want %v", c.stderr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffIndexLines(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+		field  int
+		want   []string
+	}{
+		{"empty", "", -1, nil},
+		{"last field", ":100644 100644 abc123 def456 M\tfoo.go", -1, []string{"foo.go"}},
+		{"explicit field", "M foo.go", 1, []string{"foo.go"}},
+		{"multiple lines", "M foo.go\nA bar.go", 1, []string{"foo.go", "bar.go"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := parseDiffIndexLines(c.output, c.field)
+			if len(got) != len(c.want) {
+				t.Fatalf("parseDiffIndexLines(%q, %d) = %v, want %v", c.output, c.field, got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("parseDiffIndexLines(%q, %d) = %v, want %v", c.output, c.field, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNoVCSDescribe(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "VERSION")
+	if err := os.WriteFile(filename, []byte("v1.2.3\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	v := NoVCS{Filename: filename}
+	got, err := v.Describe(context.Background())
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	if got != "v1.2.3" {
+		t.Errorf("Describe() = %q, want %q", got, "v1.2.3")
+	}
+}
+
+func TestNoVCSDescribeMissingFile(t *testing.T) {
+	v := NoVCS{Filename: filepath.Join(t.TempDir(), "DOES_NOT_EXIST")}
+	_, err := v.Describe(context.Background())
+	if !errors.Is(err, ErrNotARepo) {
+		t.Errorf("Describe() error = %v, want wrapping ErrNotARepo", err)
+	}
+}
+
+func TestDetectVCS(t *testing.T) {
+	cases := []struct {
+		name   string
+		marker string
+		want   VCS
+	}{
+		{"git", ".git", GitVCS{}},
+		{"hg", ".hg", HgVCS{}},
+		{"fossil checkout", ".fslckout", FossilVCS{}},
+		{"fossil checkout windows", "_FOSSIL_", FossilVCS{}},
+		{"none", "", NoVCS{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if c.marker != "" {
+				if err := os.Mkdir(filepath.Join(dir, c.marker), 0755); err != nil {
+					t.Fatalf("create marker: %v", err)
+				}
+			}
+
+			cwd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("Getwd: %v", err)
+			}
+			defer os.Chdir(cwd)
+			if err := os.Chdir(dir); err != nil {
+				t.Fatalf("Chdir: %v", err)
+			}
+
+			if got := detectVCS(); got != c.want {
+				t.Errorf("detectVCS() = %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,18 @@
 package vergen
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 )
 
@@ -31,58 +37,272 @@ var IgnoreFiles = []string{"version.go"}
 // wait for each git command it runs to complete before killing it.
 var Timeout = DefaultTimeout
 
+// IncludeCommitTime toggles whether CreateFile stamps the current
+// revision's commit time into VgCommitTime.
+var IncludeCommitTime = true
+
+// IncludeBranch toggles whether CreateFile stamps the current branch
+// name into VgBranch.
+var IncludeBranch = true
+
+// Errors returned by the VCS commands vergen runs, so callers can tell a
+// missing repository (which CreateFile falls back to the VERSION file
+// for) apart from a real failure.
+var (
+	// ErrNoGit is returned when the VCS command line tool itself isn't
+	// installed.
+	ErrNoGit = errors.New("vergen: VCS executable not found")
+	// ErrNotARepo is returned when the current directory isn't a
+	// repository of the detected VCS's kind.
+	ErrNotARepo = errors.New("vergen: not a VCS repository")
+	// ErrTimeout is returned when a VCS command doesn't complete within
+	// Timeout.
+	ErrTimeout = errors.New("vergen: command timed out")
+)
+
+// SemverMode, when true, makes VgVersion itself hold the SemVer 2.0.0
+// translation produced by Translator instead of the raw 'git describe
+// --tags' output. VgSemver always holds the translated (or, in a repo
+// with no tags, synthesized) value regardless of this setting.
+var SemverMode = false
+
+// Translator turns the raw output of 'git describe --tags' into a SemVer
+// 2.0.0 string. It is called whenever the VCS reports a describe string;
+// in a repo with no tags VgSemver is synthesized as "v0.0.0-pre0+g<hash>"
+// without calling it. It can be replaced to customize the translation, e.g. to match a different
+// pre-release/build metadata scheme.
+var Translator func(describe string) string = DefaultTranslator
+
+// describeRe matches the output of 'git describe --tags', optionally with
+// the "-<count>-g<hash>" suffix git appends when HEAD is not exactly on a tag.
+var describeRe = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)(?:-(\d+)-g([0-9a-f]+))?$`)
+
+// DefaultTranslator is the default Translator. An exact tag (e.g. "v1.0.0")
+// is returned unchanged. A tag with commit-count/hash suffix (e.g.
+// "v1.0.0-4-g0abcdef") has its patch version incremented and the commit
+// count and short hash folded into pre-release and build metadata
+// (e.g. "v1.0.1-pre.4+g0abcdef"), matching SemVer 2.0.0 precedence rules.
+// Output that doesn't look like a git-describe tag is returned unchanged.
+func DefaultTranslator(describe string) string {
+	describe = strings.TrimSpace(describe)
+	m := describeRe.FindStringSubmatch(describe)
+	if m == nil {
+		return describe
+	}
+	prefix, count, hash := m[1], m[5], m[6]
+	if count == "" { // Exact tag, nothing to translate.
+		return describe
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	patch, _ := strconv.Atoi(m[4])
+	return fmt.Sprintf("%s%d.%d.%d-pre.%s+g%s", prefix, major, minor, patch+1, count, hash)
+}
+
+// appendDirtySemver appends a ".dirty" pre-release identifier (or "-dirty"
+// if there is no pre-release section yet) to a SemVer string, preserving
+// any existing build metadata.
+func appendDirtySemver(semver string) string {
+	core, build := semver, ""
+	if idx := strings.Index(semver, "+"); idx != -1 {
+		core, build = semver[:idx], semver[idx:]
+	}
+	if strings.Contains(core, "-") {
+		core += ".dirty"
+	} else {
+		core += "-dirty"
+	}
+	return core + build
+}
+
 type versionData struct {
 	describeTags string
 	commit       string
 	dirty        bool
+	semver       string
+	commitTime   string
+	branch       string
+	tag          string
+	commitCount  string
+}
+
+// splitDescribeTagCount splits the output of 'git describe --tags' into
+// the nearest tag and the number of commits since it, e.g.
+// "v1.0.0-4-g0abcdef" becomes ("v1.0.0", "4"). An exact tag, with no
+// "-<count>-g<hash>" suffix, yields a commit count of "0".
+func splitDescribeTagCount(describe string) (tag, count string) {
+	idx := strings.LastIndex(describe, "-g")
+	if idx == -1 {
+		return describe, "0"
+	}
+	rest := describe[:idx]
+	cIdx := strings.LastIndex(rest, "-")
+	if cIdx == -1 {
+		return describe, "0"
+	}
+	return rest[:cIdx], rest[cIdx+1:]
 }
 
 // Create will create a file named version.go in the directory you ran
-// 'go generate', that will contain three constants:
+// 'go generate', that will contain:
 //
 // VgVersion: The version of your repo as given by 'git describe --tags'
-// plus the DirtyString variable if you have uncommitted changes.
+// plus the DirtyString variable if you have uncommitted changes. If
+// SemverMode is true this holds the same value as VgSemver instead, and
+// if the repo has no tags at all it always does (there's no raw describe
+// output to fall back to), synthesizing "v0.0.0-pre0+g<hash>".
+//
+// VgSemver: VgVersion translated into SemVer 2.0.0 by Translator.
 //
 // VgHash: The SHA1 hash of your current commit.
 //
 // VgClean: indicates whether your build is clean or it includes uncommitted changes.
+//
+// VgCommitTime: the current commit's timestamp (RFC3339Nano, UTC), if
+// IncludeCommitTime is true.
+//
+// VgBranch: the current branch name, if IncludeBranch is true.
+//
+// VgTag: the nearest tag, and VgCommitCount: the number of commits since
+// it (or "0" if you're exactly on that tag).
 func Create() error {
-	return CreateFile(DefaultPkgName + "/" + DefaultPkgName + ".go")
+	return CreateFile(PkgName + "/" + PkgName + ".go")
 }
 
 // CreateFile will work as Create() but instead of writing version.go in the
 // directory you ran 'go generate' in, it will write filename instead (which
-// may include a path).
+// may include a path). The contents are produced by Render.
 func CreateFile(filename string) error {
-	// Get version
-	version, err := runGitSingleLineReturn("git", "describe", "--tags")
+	return CreateWithContext(context.Background(), filename)
+}
+
+// CreateWithContext works like CreateFile, but every VCS command it runs
+// is bound to ctx, so the caller controls cancellation and timeout
+// instead of the package-level Timeout variable alone.
+func CreateWithContext(ctx context.Context, filename string) error {
+	data, err := gatherVersionData(ctx)
 	if err != nil {
-		version = "0.1.0"
-		//return errors.New("Could not run 'git describe --tags'. " +
-		//	"Are there any tags in your repo? Error: " + err.Error())
+		return err
 	}
 
-	// Get SHA1
-	hash, err := runGitSingleLineReturn("git", "rev-parse", "HEAD")
+	content, err := Render(PkgName, Names, data)
 	if err != nil {
-		return errors.New("Could not run 'git rev-parse HEAD' " +
-			"to get commit hash. Error: " + err.Error())
+		return err
 	}
 
-	// Get uncommitted changes, split them by line
-	diffIndex, err := runGitSingleLineReturn("git", "diff-index", "HEAD")
+	return writeVersionFile(filename, content)
+}
+
+// CreateFileWithTemplate works like CreateFile, but instead of using
+// Render it executes tmpl (a text/template string) against a VersionInfo
+// built from the gathered version data, and writes the result to
+// filename. This lets callers feed vergen's output into build systems
+// other than Go.
+func CreateFileWithTemplate(filename, tmpl string) error {
+	data, err := gatherVersionData(context.Background())
 	if err != nil {
-		errors.New("Could not run 'git diff-index HEAD' " +
-			"to detect uncommitted changes. Error: " + err.Error())
+		return err
 	}
-	var diffIndexLines []string
-	if strings.TrimSpace(diffIndex) != "" { // Because direct assignment will give us []string{""}
-		diffIndexLines = strings.Split(strings.TrimSpace(diffIndex), "\n")
+
+	t, err := template.New("vergen").Parse(tmpl)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data.toVersionInfo(PkgName)); err != nil {
+		return err
+	}
+
+	return writeVersionFile(filename, buf.String())
+}
+
+// gatherVersionData inspects the detected VCS backend and assembles the
+// versionData a Renderer (or CreateFileWithTemplate) turns into output. If
+// the backend reports ErrNotARepo, it falls back to NoVCS (reading a
+// VERSION file) instead of failing outright.
+func gatherVersionData(ctx context.Context) (versionData, error) {
+	vcs := getVCS()
+	data, err := gatherFromVCS(ctx, vcs)
+	if errors.Is(err, ErrNotARepo) {
+		if _, isNoVCS := vcs.(NoVCS); !isNoVCS {
+			log.Printf("No VCS repository detected, falling back to the VERSION file")
+			return gatherFromVCS(ctx, NoVCS{})
+		}
+	}
+	return data, err
+}
+
+// gatherFromVCS runs the VCS queries CreateFile needs against vcs.
+func gatherFromVCS(ctx context.Context, vcs VCS) (versionData, error) {
+	// Get version. A plain describe failure (e.g. no tags in the repo)
+	// falls back to a synthesized version below; ErrNotARepo is handled by
+	// gatherVersionData's NoVCS fallback, and ErrTimeout/ErrNoGit are real
+	// failures that must propagate rather than be mistaken for "no tags".
+	version, err := vcs.Describe(ctx)
+	if err != nil && (errors.Is(err, ErrTimeout) || errors.Is(err, ErrNoGit) || errors.Is(err, ErrNotARepo)) {
+		return versionData{}, fmt.Errorf("could not run 'git describe --tags': %w", err)
+	}
+	noTags := err != nil
+
+	// Get SHA1
+	hash, err := vcs.Revision(ctx)
+	if err != nil {
+		return versionData{}, fmt.Errorf("could not get the current revision: %w", err)
+	}
+
+	// Translate into SemVer 2.0.0. With no tags in the repo, VgVersion
+	// itself becomes the synthesized "v0.0.0-pre0+g<hash>" (there's no
+	// raw describe output to fall back to); otherwise it's translated by
+	// Translator, same as VgSemver.
+	var semver string
+	if noTags {
+		shortHash := hash
+		if len(shortHash) > 7 {
+			shortHash = shortHash[:7]
+		}
+		semver = fmt.Sprintf("v0.0.0-pre0+g%s", shortHash)
+		version = semver
+	} else {
+		semver = Translator(version)
+	}
+
+	tag, commitCount := "", ""
+	if !noTags {
+		tag, commitCount = splitDescribeTagCount(version)
+	}
+
+	// NoVCS has no commit to read a time or branch from; leave both
+	// fields empty rather than stamping the zero time.Time / empty
+	// string its stub methods would otherwise report as if it were real
+	// data.
+	_, isNoVCS := vcs.(NoVCS)
+
+	var commitTime string
+	if IncludeCommitTime && !isNoVCS {
+		t, err := vcs.CommitTime(ctx)
+		if err != nil {
+			return versionData{}, fmt.Errorf("could not get the current commit time: %w", err)
+		}
+		commitTime = t.UTC().Format(time.RFC3339Nano)
+	}
+
+	var branch string
+	if IncludeBranch && !isNoVCS {
+		branch, err = vcs.Branch(ctx)
+		if err != nil {
+			return versionData{}, fmt.Errorf("could not get the current branch: %w", err)
+		}
+	}
+
+	// Get uncommitted changes
+	changedFiles, err := vcs.Uncommitted(ctx)
+	if err != nil {
+		return versionData{}, fmt.Errorf("could not detect uncommitted changes: %w", err)
 	}
 
 	uncommittedChanges := false
 UNCOMMITTED:
-	for _, v := range diffIndexLines { // For each uncommitted, changed file
+	for _, v := range changedFiles { // For each uncommitted, changed file
 		matches := 0
 		for _, v2 := range IgnoreFiles { // Check against each of blacklisted files
 			if strings.Contains(v, v2) {
@@ -95,36 +315,78 @@ UNCOMMITTED:
 		}
 	}
 	if uncommittedChanges {
-		version += DirtyString
+		semver = appendDirtySemver(semver)
+		if noTags {
+			version = semver
+		} else {
+			version += DirtyString
+		}
+	}
+
+	if SemverMode {
+		version = semver
 	}
 
 	log.Printf("Setting VgVersion to: %v\n", version)
+	log.Printf("Setting VgSemver to: %v\n", semver)
 	log.Printf("Setting VgHash to: %v\n", hash)
 	log.Printf("Setting VgClean to: %v\n", !uncommittedChanges)
+	log.Printf("Setting VgCommitTime to: %v\n", commitTime)
+	log.Printf("Setting VgBranch to: %v\n", branch)
 
-	err = writeVersionFile(filename, versionData{version, hash, uncommittedChanges})
-	return err
+	return versionData{
+		describeTags: version,
+		commit:       hash,
+		dirty:        uncommittedChanges,
+		semver:       semver,
+		commitTime:   commitTime,
+		branch:       branch,
+		tag:          tag,
+		commitCount:  commitCount,
+	}, nil
 }
 
-// Run a command and return the results and possible errors.
-func runGitSingleLineReturn(command string, args ...string) (string, error) {
-	cmd := exec.Command(command, args...)
+// runCommandSingleLineReturn runs a VCS command line tool, bound to ctx
+// and to Timeout (whichever elapses first), and returns its trimmed
+// stdout. It is shared by the GitVCS, HgVCS and FossilVCS backends.
+func runCommandSingleLineReturn(ctx context.Context, command string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	timer := time.AfterFunc(Timeout, func() { cmd.Process.Kill() })
-	out, err := cmd.CombinedOutput()
-	timer.Stop()
+	err := cmd.Run()
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", ErrTimeout
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", ErrNoGit
+		}
+		if isNotARepoMessage(stderr.String()) {
+			return "", ErrNotARepo
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", err, msg)
+		}
 		return "", err
 	}
 
-	result := strings.TrimSpace(string(out))
-	return result, nil
+	return strings.TrimSpace(stdout.String()), nil
 }
 
-// Write the version file.
-func writeVersionFile(filename string, data versionData) error {
-	if _, err := os.Stat(DefaultPkgName); os.IsNotExist(err) {
-		os.Mkdir(DefaultPkgName, os.ModePerm)
+// writeVersionFile creates filename (and its parent directory, if it
+// doesn't exist yet) and writes content to it.
+func writeVersionFile(filename, content string) error {
+	if dir := filepath.Dir(filename); dir != "." && dir != "" {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				return err
+			}
+		}
 	}
 	file, err := os.Create(filename)
 	if err != nil {
@@ -132,15 +394,6 @@ func writeVersionFile(filename string, data versionData) error {
 	}
 	defer file.Close()
 
-	out := fmt.Sprintf(`package %s
-// auto generated by github.com/Akagi201/utils-go/vergen
-const (
-	VgVersion   = "%s"
-	VgHash      = "%s"
-	VgClean     = %v
-)
-`, DefaultPkgName, data.describeTags, data.commit, !data.dirty)
-
-	_, err = file.Write([]byte(out))
+	_, err = file.WriteString(content)
 	return err
 }
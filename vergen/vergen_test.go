@@ -0,0 +1,63 @@
+package vergen
+
+import "testing"
+
+func TestDefaultTranslator(t *testing.T) {
+	cases := []struct {
+		name     string
+		describe string
+		want     string
+	}{
+		{"exact tag", "v1.0.0", "v1.0.0"},
+		{"exact tag no v prefix", "1.0.0", "1.0.0"},
+		{"commits since tag", "v1.0.0-4-g0abcdef", "v1.0.1-pre.4+g0abcdef"},
+		{"not a git-describe string", "garbage", "garbage"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := DefaultTranslator(c.describe); got != c.want {
+				t.Errorf("DefaultTranslator(%q) = %q, want %q", c.describe, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAppendDirtySemver(t *testing.T) {
+	cases := []struct {
+		name   string
+		semver string
+		want   string
+	}{
+		{"no pre-release or build", "v1.0.0", "v1.0.0-dirty"},
+		{"existing pre-release", "v1.0.1-pre.4+g0abcdef", "v1.0.1-pre.4.dirty+g0abcdef"},
+		{"no pre-release with build", "v0.0.0+g0abcdef", "v0.0.0-dirty+g0abcdef"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := appendDirtySemver(c.semver); got != c.want {
+				t.Errorf("appendDirtySemver(%q) = %q, want %q", c.semver, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSplitDescribeTagCount(t *testing.T) {
+	cases := []struct {
+		name      string
+		describe  string
+		wantTag   string
+		wantCount string
+	}{
+		{"exact tag", "v1.0.0", "v1.0.0", "0"},
+		{"commits since tag", "v1.0.0-4-g0abcdef", "v1.0.0", "4"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tag, count := splitDescribeTagCount(c.describe)
+			if tag != c.wantTag || count != c.wantCount {
+				t.Errorf("splitDescribeTagCount(%q) = (%q, %q), want (%q, %q)",
+					c.describe, tag, count, c.wantTag, c.wantCount)
+			}
+		})
+	}
+}